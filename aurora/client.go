@@ -0,0 +1,129 @@
+// Package aurora speaks directly to the Aurora scheduler's Thrift API
+// for the packer calls jenkins_uploader needs, replacing
+// exec.Command("aurora", ...) plus screen-scraping SHA1s out of its
+// stdout. Shelling out broke every time the CLI's output format
+// changed and paid a fork+exec per cluster per package; this client
+// pays one HTTP round trip per call instead.
+package aurora
+
+import (
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "os"
+  "os/user"
+  "path/filepath"
+
+  "github.com/apache/thrift/lib/go/thrift"
+)
+
+// Version is a single packer package version as the scheduler reports
+// it.
+type Version struct {
+  Revision string
+  MD5      string
+}
+
+// ClusterConfig is the subset of an entry in the Aurora CLI's
+// clusters.json this client needs to reach a scheduler.
+type ClusterConfig struct {
+  Name         string `json:"name"`
+  SchedulerURI string `json:"scheduler_uri"`
+}
+
+// LoadClusters reads cluster definitions from path, defaulting to
+// ~/.aurora/clusters.json, mirroring the CLI's own cluster resolution
+// so operators don't maintain two copies of the same file.
+func LoadClusters(path string) (map[string]ClusterConfig, error) {
+  if path == "" {
+    u, err := user.Current()
+    if err != nil {
+      return nil, err
+    }
+    path = filepath.Join(u.HomeDir, ".aurora", "clusters.json")
+  }
+
+  f, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+
+  var list []ClusterConfig
+  if err := json.NewDecoder(f).Decode(&list); err != nil {
+    return nil, err
+  }
+
+  clusters := make(map[string]ClusterConfig, len(list))
+  for _, c := range list {
+    clusters[c.Name] = c
+  }
+  return clusters, nil
+}
+
+// Client speaks the scheduler's Thrift API for a single cluster. It is
+// not safe for concurrent use; callers talking to several clusters (or
+// the same cluster from several goroutines) should create one Client
+// per call, which is cheap since it only wraps an HTTP transport.
+type Client struct {
+  cluster string
+  trans   thrift.TTransport
+  proto   thrift.TProtocol
+}
+
+// NewClient dials the scheduler for cluster, resolved via clusters (as
+// returned by LoadClusters).
+func NewClient(clusters map[string]ClusterConfig, cluster string) (*Client, error) {
+  cfg, ok := clusters[cluster]
+  if !ok {
+    return nil, fmt.Errorf("unknown aurora cluster %q, check clusters.json", cluster)
+  }
+
+  trans, err := thrift.NewTHttpPostClient(cfg.SchedulerURI + "/api")
+  if err != nil {
+    return nil, err
+  }
+  proto := thrift.NewTJSONProtocolFactory().GetProtocol(trans)
+
+  return &Client{cluster: cluster, trans: trans, proto: proto}, nil
+}
+
+// Close releases the underlying Thrift transport.
+func (c *Client) Close() error {
+  return c.trans.Close()
+}
+
+// PackageVersions returns every version of pkg registered under role
+// in this client's cluster, oldest first, matching the order the
+// `aurora package_versions` CLI printed.
+func (c *Client) PackageVersions(role, pkg string) ([]Version, error) {
+  if err := c.trans.Open(); err != nil {
+    return nil, err
+  }
+  defer c.trans.Close()
+
+  if err := writeGetVersionsCall(c.proto, role, pkg); err != nil {
+    return nil, err
+  }
+  return readVersionsResult(c.proto)
+}
+
+// AddPackageVersion reads file's content off disk and uploads it to
+// packer as a new version of pkg under role, tagged with metadata (the
+// git revision being deployed).
+func (c *Client) AddPackageVersion(role, pkg, file, metadata string) error {
+  content, err := ioutil.ReadFile(file)
+  if err != nil {
+    return err
+  }
+
+  if err := c.trans.Open(); err != nil {
+    return err
+  }
+  defer c.trans.Close()
+
+  if err := writeAddVersionCall(c.proto, role, pkg, file, metadata, content); err != nil {
+    return err
+  }
+  return readAddVersionResult(c.proto)
+}
@@ -0,0 +1,268 @@
+package aurora
+
+import (
+  "io"
+  "net/http"
+  "net/http/httptest"
+  "os"
+  "path/filepath"
+  "testing"
+
+  "github.com/apache/thrift/lib/go/thrift"
+)
+
+// fakeScheduler answers just enough of the AuroraAdmin Thrift JSON
+// protocol over HTTP to exercise Client.PackageVersions and
+// Client.AddPackageVersion without a live scheduler.
+type fakeScheduler struct {
+  versions []Version
+  addErr   string // non-empty: addVersion replies with this error instead of success
+
+  gotRole, gotPkg, gotFile, gotMetadata string
+  gotContent                            []byte
+}
+
+func (f *fakeScheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  body, err := io.ReadAll(r.Body)
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusInternalServerError)
+    return
+  }
+
+  in := thrift.NewTMemoryBufferLen(len(body))
+  in.Write(body)
+  inProto := thrift.NewTJSONProtocolFactory().GetProtocol(in)
+
+  name, _, _, err := inProto.ReadMessageBegin()
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusInternalServerError)
+    return
+  }
+
+  out := thrift.NewTMemoryBuffer()
+  outProto := thrift.NewTJSONProtocolFactory().GetProtocol(out)
+
+  switch name {
+  case "getVersions":
+    err = readStruct(inProto, func(fieldID int16) error {
+      var err error
+      switch fieldID {
+      case 1:
+        f.gotRole, err = inProto.ReadString()
+      case 2:
+        f.gotPkg, err = inProto.ReadString()
+      default:
+        err = thrift.SkipDefaultDepth(inProto, thrift.STRUCT)
+      }
+      return err
+    })
+    if err == nil {
+      err = f.writeVersionsResult(outProto)
+    }
+  case "addVersion":
+    err = readStruct(inProto, func(fieldID int16) error {
+      var err error
+      switch fieldID {
+      case 1:
+        f.gotRole, err = inProto.ReadString()
+      case 2:
+        f.gotPkg, err = inProto.ReadString()
+      case 3:
+        f.gotFile, err = inProto.ReadString()
+      case 4:
+        f.gotMetadata, err = inProto.ReadString()
+      case 5:
+        f.gotContent, err = inProto.ReadBinary()
+      default:
+        err = thrift.SkipDefaultDepth(inProto, thrift.STRUCT)
+      }
+      return err
+    })
+    if err == nil {
+      err = f.writeAddVersionResult(outProto)
+    }
+  default:
+    http.Error(w, "unknown method "+name, http.StatusBadRequest)
+    return
+  }
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusInternalServerError)
+    return
+  }
+
+  w.Header().Set("Content-Type", "application/x-thrift")
+  w.Write(out.Bytes())
+}
+
+func (f *fakeScheduler) writeVersionsResult(p thrift.TProtocol) error {
+  if err := p.WriteMessageBegin("getVersions", thrift.REPLY, 0); err != nil {
+    return err
+  }
+  if err := p.WriteStructBegin("getVersions_result"); err != nil {
+    return err
+  }
+  if err := p.WriteFieldBegin("success", thrift.LIST, 1); err != nil {
+    return err
+  }
+  if err := p.WriteListBegin(thrift.STRUCT, len(f.versions)); err != nil {
+    return err
+  }
+  for _, v := range f.versions {
+    if err := p.WriteStructBegin("PackageVersion"); err != nil {
+      return err
+    }
+    if err := writeField(p, 1, v.Revision); err != nil {
+      return err
+    }
+    if err := writeField(p, 2, v.MD5); err != nil {
+      return err
+    }
+    if err := p.WriteFieldStop(); err != nil {
+      return err
+    }
+    if err := p.WriteStructEnd(); err != nil {
+      return err
+    }
+  }
+  if err := p.WriteListEnd(); err != nil {
+    return err
+  }
+  if err := p.WriteFieldEnd(); err != nil {
+    return err
+  }
+  if err := p.WriteFieldStop(); err != nil {
+    return err
+  }
+  if err := p.WriteStructEnd(); err != nil {
+    return err
+  }
+  if err := p.WriteMessageEnd(); err != nil {
+    return err
+  }
+  return p.Flush(nil)
+}
+
+func (f *fakeScheduler) writeAddVersionResult(p thrift.TProtocol) error {
+  if err := p.WriteMessageBegin("addVersion", thrift.REPLY, 0); err != nil {
+    return err
+  }
+  if err := p.WriteStructBegin("addVersion_result"); err != nil {
+    return err
+  }
+  respCode, message := int32(0), ""
+  if f.addErr != "" {
+    respCode, message = 1, f.addErr
+  }
+  if err := p.WriteFieldBegin("", thrift.I32, 1); err != nil {
+    return err
+  }
+  if err := p.WriteI32(respCode); err != nil {
+    return err
+  }
+  if err := p.WriteFieldEnd(); err != nil {
+    return err
+  }
+  if err := writeField(p, 2, message); err != nil {
+    return err
+  }
+  if err := p.WriteFieldStop(); err != nil {
+    return err
+  }
+  if err := p.WriteStructEnd(); err != nil {
+    return err
+  }
+  if err := p.WriteMessageEnd(); err != nil {
+    return err
+  }
+  return p.Flush(nil)
+}
+
+func TestClientPackageVersions(t *testing.T) {
+  fake := &fakeScheduler{versions: []Version{
+    {Revision: "abc123", MD5: "deadbeef"},
+    {Revision: "def456", MD5: "cafef00d"},
+  }}
+  srv := httptest.NewServer(fake)
+  defer srv.Close()
+
+  clusters := map[string]ClusterConfig{"test": {Name: "test", SchedulerURI: srv.URL}}
+  client, err := NewClient(clusters, "test")
+  if err != nil {
+    t.Fatalf("NewClient: %v", err)
+  }
+  defer client.Close()
+
+  versions, err := client.PackageVersions("jenkins", "my-package")
+  if err != nil {
+    t.Fatalf("PackageVersions: %v", err)
+  }
+  if fake.gotRole != "jenkins" || fake.gotPkg != "my-package" {
+    t.Fatalf("scheduler saw role=%q pkg=%q, want jenkins/my-package", fake.gotRole, fake.gotPkg)
+  }
+  if len(versions) != len(fake.versions) {
+    t.Fatalf("got %d versions, want %d", len(versions), len(fake.versions))
+  }
+  for i, v := range versions {
+    if v != fake.versions[i] {
+      t.Errorf("version %d = %+v, want %+v", i, v, fake.versions[i])
+    }
+  }
+}
+
+func TestClientAddPackageVersion(t *testing.T) {
+  fake := &fakeScheduler{}
+  srv := httptest.NewServer(fake)
+  defer srv.Close()
+
+  clusters := map[string]ClusterConfig{"test": {Name: "test", SchedulerURI: srv.URL}}
+  client, err := NewClient(clusters, "test")
+  if err != nil {
+    t.Fatalf("NewClient: %v", err)
+  }
+  defer client.Close()
+
+  file := filepath.Join(t.TempDir(), "my-package.zip")
+  content := []byte("fake package contents")
+  if err := os.WriteFile(file, content, 0644); err != nil {
+    t.Fatalf("WriteFile: %v", err)
+  }
+
+  if err := client.AddPackageVersion("jenkins", "my-package", file, "rev-1"); err != nil {
+    t.Fatalf("AddPackageVersion: %v", err)
+  }
+  if fake.gotRole != "jenkins" || fake.gotPkg != "my-package" || fake.gotFile != file || fake.gotMetadata != "rev-1" {
+    t.Fatalf("scheduler saw role=%q pkg=%q file=%q metadata=%q, want jenkins/my-package/%s/rev-1",
+      fake.gotRole, fake.gotPkg, fake.gotFile, fake.gotMetadata, file)
+  }
+  if string(fake.gotContent) != string(content) {
+    t.Fatalf("scheduler saw content %q, want %q", fake.gotContent, content)
+  }
+}
+
+func TestClientAddPackageVersionError(t *testing.T) {
+  fake := &fakeScheduler{addErr: "package already exists"}
+  srv := httptest.NewServer(fake)
+  defer srv.Close()
+
+  clusters := map[string]ClusterConfig{"test": {Name: "test", SchedulerURI: srv.URL}}
+  client, err := NewClient(clusters, "test")
+  if err != nil {
+    t.Fatalf("NewClient: %v", err)
+  }
+  defer client.Close()
+
+  file := filepath.Join(t.TempDir(), "my-package.zip")
+  if err := os.WriteFile(file, []byte("fake package contents"), 0644); err != nil {
+    t.Fatalf("WriteFile: %v", err)
+  }
+
+  if err := client.AddPackageVersion("jenkins", "my-package", file, "rev-1"); err == nil {
+    t.Fatal("expected an error from a scheduler reply with a non-zero response code")
+  }
+}
+
+func TestNewClientUnknownCluster(t *testing.T) {
+  if _, err := NewClient(map[string]ClusterConfig{}, "nope"); err == nil {
+    t.Fatal("expected an error for an unknown cluster")
+  }
+}
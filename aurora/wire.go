@@ -0,0 +1,190 @@
+package aurora
+
+import (
+  "fmt"
+
+  "github.com/apache/thrift/lib/go/thrift"
+)
+
+// The scheduler's packer calls live on the AuroraAdmin Thrift service.
+// We only need two of its methods, so rather than vendor the full
+// generated gen-go/aurora bindings we speak them directly over the
+// TProtocol this package already opened.
+
+func writeGetVersionsCall(p thrift.TProtocol, role, pkg string) error {
+  if err := p.WriteMessageBegin("getVersions", thrift.CALL, 0); err != nil {
+    return err
+  }
+  if err := p.WriteStructBegin("getVersions_args"); err != nil {
+    return err
+  }
+  if err := writeField(p, 1, role); err != nil {
+    return err
+  }
+  if err := writeField(p, 2, pkg); err != nil {
+    return err
+  }
+  if err := p.WriteFieldStop(); err != nil {
+    return err
+  }
+  if err := p.WriteStructEnd(); err != nil {
+    return err
+  }
+  if err := p.WriteMessageEnd(); err != nil {
+    return err
+  }
+  return p.Flush(nil)
+}
+
+func readVersionsResult(p thrift.TProtocol) ([]Version, error) {
+  if _, _, _, err := p.ReadMessageBegin(); err != nil {
+    return nil, err
+  }
+  defer p.ReadMessageEnd()
+
+  var versions []Version
+  if err := readStruct(p, func(fieldID int16) error {
+    if fieldID != 1 {
+      return thrift.SkipDefaultDepth(p, thrift.STRUCT)
+    }
+    _, size, err := p.ReadListBegin()
+    if err != nil {
+      return err
+    }
+    for i := 0; i < size; i++ {
+      v, err := readVersion(p)
+      if err != nil {
+        return err
+      }
+      versions = append(versions, v)
+    }
+    return p.ReadListEnd()
+  }); err != nil {
+    return nil, err
+  }
+  return versions, nil
+}
+
+func readVersion(p thrift.TProtocol) (Version, error) {
+  var v Version
+  err := readStruct(p, func(fieldID int16) error {
+    var err error
+    switch fieldID {
+    case 1:
+      v.Revision, err = p.ReadString()
+    case 2:
+      v.MD5, err = p.ReadString()
+    default:
+      err = thrift.SkipDefaultDepth(p, thrift.STRUCT)
+    }
+    return err
+  })
+  return v, err
+}
+
+func writeAddVersionCall(p thrift.TProtocol, role, pkg, file, metadata string, content []byte) error {
+  if err := p.WriteMessageBegin("addVersion", thrift.CALL, 0); err != nil {
+    return err
+  }
+  if err := p.WriteStructBegin("addVersion_args"); err != nil {
+    return err
+  }
+  for i, s := range []string{role, pkg, file, metadata} {
+    if err := writeField(p, int16(i+1), s); err != nil {
+      return err
+    }
+  }
+  if err := writeBinaryField(p, 5, content); err != nil {
+    return err
+  }
+  if err := p.WriteFieldStop(); err != nil {
+    return err
+  }
+  if err := p.WriteStructEnd(); err != nil {
+    return err
+  }
+  if err := p.WriteMessageEnd(); err != nil {
+    return err
+  }
+  return p.Flush(nil)
+}
+
+func readAddVersionResult(p thrift.TProtocol) error {
+  if _, _, _, err := p.ReadMessageBegin(); err != nil {
+    return err
+  }
+  defer p.ReadMessageEnd()
+
+  var respCode int32
+  var message string
+  err := readStruct(p, func(fieldID int16) error {
+    var err error
+    switch fieldID {
+    case 1:
+      var v int32
+      v, err = p.ReadI32()
+      respCode = v
+    case 2:
+      message, err = p.ReadString()
+    default:
+      err = thrift.SkipDefaultDepth(p, thrift.STRUCT)
+    }
+    return err
+  })
+  if err != nil {
+    return err
+  }
+  if respCode != 0 {
+    return fmt.Errorf("aurora: %s", message)
+  }
+  return nil
+}
+
+func writeField(p thrift.TProtocol, id int16, value string) error {
+  if err := p.WriteFieldBegin("", thrift.STRING, id); err != nil {
+    return err
+  }
+  if err := p.WriteString(value); err != nil {
+    return err
+  }
+  return p.WriteFieldEnd()
+}
+
+// writeBinaryField writes value as a Thrift binary field, which shares
+// STRING's wire type with a plain string field.
+func writeBinaryField(p thrift.TProtocol, id int16, value []byte) error {
+  if err := p.WriteFieldBegin("", thrift.STRING, id); err != nil {
+    return err
+  }
+  if err := p.WriteBinary(value); err != nil {
+    return err
+  }
+  return p.WriteFieldEnd()
+}
+
+// readStruct walks a Thrift struct, invoking fn for every field so
+// callers only deal with the fields they care about.
+func readStruct(p thrift.TProtocol, fn func(fieldID int16) error) error {
+  if _, err := p.ReadStructBegin(); err != nil {
+    return err
+  }
+  for {
+    _, typeID, fieldID, err := p.ReadFieldBegin()
+    if err != nil {
+      return err
+    }
+    if typeID == thrift.STOP {
+      break
+    }
+    if err := fn(fieldID); err != nil {
+      return err
+    }
+    if err := p.ReadFieldEnd(); err != nil {
+      return err
+    }
+  }
+  if err := p.ReadStructEnd(); err != nil {
+    return err
+  }
+  return nil
+}
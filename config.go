@@ -0,0 +1,59 @@
+package main
+
+import (
+  "io/ioutil"
+  "os"
+  "os/user"
+  "path/filepath"
+
+  "gopkg.in/yaml.v2"
+)
+
+// Config is the top-level settings file, searched for at
+// ./jenkins_uploader.yaml, ~/.jenkins_uploader.yaml and
+// $XDG_CONFIG_HOME/jenkins_uploader.yaml in that order. It replaces
+// the hard-coded AllClusters so adding a region, or retargeting
+// concurrency limits, doesn't need a recompile.
+type Config struct {
+  Clusters               []string `yaml:"clusters"`
+  AuroraRole             string   `yaml:"aurora_role"`
+  DownloadDir            string   `yaml:"download_dir"`
+  MaxConcurrentDownloads int      `yaml:"max_concurrent_downloads"`
+  MaxConcurrentUploads   int      `yaml:"max_concurrent_uploads"`
+}
+
+// configPaths returns the search order for jenkins_uploader.yaml.
+func configPaths() []string {
+  var paths []string
+  if pwd, err := os.Getwd(); err == nil {
+    paths = append(paths, filepath.Join(pwd, "jenkins_uploader.yaml"))
+  }
+  if u, err := user.Current(); err == nil {
+    paths = append(paths, filepath.Join(u.HomeDir, ".jenkins_uploader.yaml"))
+  }
+  if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+    paths = append(paths, filepath.Join(xdg, "jenkins_uploader.yaml"))
+  }
+  return paths
+}
+
+// loadConfig reads the first config file found in configPaths(),
+// returning a zero Config (every setting falls back to its built-in
+// default) if none exist.
+func loadConfig() (Config, error) {
+  for _, p := range configPaths() {
+    data, err := ioutil.ReadFile(p)
+    if err != nil {
+      if os.IsNotExist(err) {
+        continue
+      }
+      return Config{}, err
+    }
+    var cfg Config
+    if err := yaml.Unmarshal(data, &cfg); err != nil {
+      return Config{}, err
+    }
+    return cfg, nil
+  }
+  return Config{}, nil
+}
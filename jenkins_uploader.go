@@ -7,23 +7,29 @@
 package main
 
 import (
-  "bytes"
+  "context"
+  "crypto/sha256"
+  "encoding/hex"
   "encoding/json"
   "flag"
+  "fmt"
   "io"
-  "log"
+  "io/ioutil"
   "os"
-  "os/exec"
+  "os/signal"
   "os/user"
   "path"
   "path/filepath"
-  "regexp"
   "net/http"
   "strings"
-)
 
-// a sha1 is 40 characters
-var shaMatch = regexp.MustCompile("[0-9a-f]{40}")
+  "golang.org/x/crypto/openpgp"
+  "github.com/sirupsen/logrus"
+
+  "github.com/sk8erwitskil/jenkins_uploader_go/aurora"
+  "github.com/sk8erwitskil/jenkins_uploader_go/keyring"
+  "github.com/sk8erwitskil/jenkins_uploader_go/transfer"
+)
 
 // accept .zip, .tgz and .gz files
 var acceptableMIMETypes = []string{
@@ -32,26 +38,45 @@ var acceptableMIMETypes = []string{
   "application/zip",
 }
 
+// AllClusters is the global default cluster set, overridden by the
+// "clusters" key in jenkins_uploader.yaml if present.
 var AllClusters = []string{
   "atla",
   "smf1",
 }
 
 type Package struct {
-  Artifact   string
-  File       string
-  Project    string
-  Revision   string
-  NeedUpdate map[string]bool
-  Updated    map[string]bool  // whether or not the package was successfully updated in packer for each cluster
-  Valid      bool  // whether or not the Artifact url has a MIMEType from acceptableMIMETypes
+  Artifact     string
+  Checksum     string  // expected SHA256 of the downloaded artifact; skipped if empty
+  SignatureURL string  // detached .asc signature for Artifact; skipped if empty
+  KeyringPath  string  // overrides keyring.DefaultPath() for this package
+  Clusters     []string  // overrides AllClusters for this package, if non-empty
+  File         string
+  Project      string
+  Revision     string
+  NeedUpdate    map[string]bool
+  Updated       map[string]bool  // whether or not the package was successfully updated in packer for each cluster
+  ClusterErrors map[string]string  // cluster -> error from UpdatePackage, if any, for the run report
+  Valid         bool  // whether or not the Artifact url has a MIMEType from acceptableMIMETypes; cleared if the download fails its Checksum
+  Verified      bool  // whether the signature check in VerifySignature passed (true if SignatureURL is unset); VerifySignature only runs for downloaded packages
 }
 
-type FuturePkg chan *Package
+// logWatcher is a transfer.Watcher that forwards job progress to the
+// run's structured logger, which is all main needs until a richer UI exists.
+type logWatcher struct{}
 
-type Futures struct {
-  Channels        FuturePkg
-  ItemsInChannels int
+func (logWatcher) OnProgress(key, kind, msg string) {
+  logger.WithFields(logrus.Fields{"phase": kind, "job": key}).Info(msg)
+}
+
+// EffectiveClusters returns p.Clusters if the manifest set one,
+// otherwise the global AllClusters, so a package can target a subset
+// of datacenters without every other package having to do the same.
+func (p *Package) EffectiveClusters() []string {
+  if len(p.Clusters) > 0 {
+    return p.Clusters
+  }
+  return AllClusters
 }
 
 func stringInArray(s string, list []string) bool {
@@ -63,97 +88,257 @@ func stringInArray(s string, list []string) bool {
   return false
 }
 
-func decodeJsonFile(jsonFile string) FuturePkg {
-  future := make(FuturePkg)
-  go func() {
-    p := &Package{}
+func decodeJsonFile(jsonFile string) (*Package, error) {
+  p := &Package{}
+
+  content, err := os.Open(jsonFile)
+  if err != nil {
+    return nil, err
+  }
+  defer content.Close()
+
+  if err := json.NewDecoder(content).Decode(p); err != nil {
+    return nil, err
+  }
+
+  p.Project = strings.Split(path.Base(jsonFile), ".json")[0]
+  return p, nil
+}
+
+// etagFile returns the sidecar path used to remember which ETag a
+// partial download belongs to, so a resumed download isn't appended
+// to a file the server has since replaced.
+func etagFile(outname string) string {
+  return outname + ".etag"
+}
+
+// downloadResult is what a deduped download job hands back to every
+// package that submitted the same Artifact, so each one can apply it
+// independently instead of only the package whose closure actually ran
+// the transfer.
+type downloadResult struct {
+  path   string
+  sha256 string
+}
+
+// downloadCachePath returns where DownloadArtifact stores artifact,
+// keyed by its URL so two packages pointing at the same Artifact share
+// one file on disk as well as one transfer.
+func downloadCachePath(basedir, artifact string) string {
+  sum := sha256.Sum256([]byte(artifact))
+  return path.Join(basedir, ".cache", hex.EncodeToString(sum[:])+"-"+path.Base(artifact))
+}
+
+func (p *Package) DownloadArtifact(mgr *transfer.Manager, basedir string, bars *progressPool) *transfer.Job {
+  outname := downloadCachePath(basedir, p.Artifact)
+
+  return mgr.Download(p.Artifact, logWatcher{}, func(ctx context.Context) (interface{}, error) {
+    outpath := path.Dir(outname)
 
-    content, err := os.Open(jsonFile)
+    if err := os.MkdirAll(outpath, 0777); err != nil && !os.IsExist(err) {
+      return nil, err
+    }
+
+    headReq, err := http.NewRequest("HEAD", p.Artifact, nil)
+    if err != nil {
+      return nil, err
+    }
+    headResp, err := http.DefaultClient.Do(headReq.WithContext(ctx))
     if err != nil {
-      log.Print("Error:", err)
+      return nil, err
+    }
+    headResp.Body.Close()
+    if headResp.StatusCode != http.StatusOK {
+      return nil, fmt.Errorf("HEAD %s: unexpected status %s", p.Artifact, headResp.Status)
+    }
+    total := headResp.ContentLength
+    etag := headResp.Header.Get("ETag")
+
+    var resumeAt int64
+    if fi, statErr := os.Stat(outname); statErr == nil {
+      prevEtag, _ := ioutil.ReadFile(etagFile(outname))
+      if etag != "" && string(prevEtag) == etag {
+        resumeAt = fi.Size()
+        pkgLog(p, "download").WithField("resume_at", resumeAt).Info("resuming download")
+      } else {
+        os.Remove(outname)
+      }
     }
 
-    jsonParser := json.NewDecoder(content)
-    err = jsonParser.Decode(p)
+    req, err := http.NewRequest("GET", p.Artifact, nil)
+    if err != nil {
+      return nil, err
+    }
+    if resumeAt > 0 {
+      req.Header.Set("Range", fmt.Sprintf("bytes=%d-", resumeAt))
+    }
+    pkgLog(p, "download").WithField("dest", outname).Info("downloading artifact")
+    response, err := http.DefaultClient.Do(req.WithContext(ctx))
     if err != nil {
-      log.Print("Error:", err)
+      return nil, err
     }
+    defer response.Body.Close()
 
-    p.Project = strings.Split(path.Base(jsonFile), ".json")[0]
-    future <- p
-  }()
-  return future
+    if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusPartialContent {
+      return nil, fmt.Errorf("GET %s: unexpected status %s", p.Artifact, response.Status)
+    }
+
+    hasher := sha256.New()
+    flags := os.O_CREATE | os.O_WRONLY
+    if resumeAt > 0 && response.StatusCode == http.StatusPartialContent {
+      flags |= os.O_APPEND
+      if existing, err := os.Open(outname); err == nil {
+        io.CopyN(hasher, existing, resumeAt)
+        existing.Close()
+      }
+    } else {
+      flags |= os.O_TRUNC
+      resumeAt = 0
+    }
+
+    output, err := os.OpenFile(outname, flags, 0644)
+    if err != nil {
+      return nil, err
+    }
+    defer output.Close()
+
+    if etag != "" {
+      ioutil.WriteFile(etagFile(outname), []byte(etag), 0644)
+    }
+
+    bar := bars.newBar(p.Project, total)
+    defer bar.Finish()
+    if resumeAt > 0 {
+      bar.SetCurrent(resumeAt)
+    }
+
+    n, err := io.Copy(io.MultiWriter(bar.NewProxyWriter(output), hasher), response.Body)
+    if err != nil {
+      return nil, err
+    }
+    pkgLog(p, "download").WithField("bytes", n).Info("download complete")
+    os.Remove(etagFile(outname))
+
+    return downloadResult{path: outname, sha256: hex.EncodeToString(hasher.Sum(nil))}, nil
+  })
 }
 
-func (p *Package) DownloadArtifact(basedir string) FuturePkg {
-  future := make(FuturePkg)
-  go func() {
-    outpath := path.Join(basedir, p.Project)
-    outname := path.Join(outpath, path.Base(p.Artifact))
-    log.Print("Downloading ", p.Artifact, " to ", outname)
+// applyDownload reads job's result, the download DownloadArtifact
+// submitted on p's behalf (possibly run by a different package sharing
+// p.Artifact), and applies it to p: checking p.Checksum and setting
+// p.File. Every package sharing an Artifact must call this themselves,
+// since only one of them actually ran the transfer.
+func (p *Package) applyDownload(job *transfer.Job) error {
+  res, err := job.Wait()
+  if err != nil {
+    return err
+  }
+  dl := res.(downloadResult)
+  if p.Checksum != "" && dl.sha256 != p.Checksum {
+    p.Valid = false
+    return fmt.Errorf("checksum mismatch for %s: got %s, want %s", p.Project, dl.sha256, p.Checksum)
+  }
+  p.File = dl.path
+  return nil
+}
+
+// VerifySignature checks Artifact's detached OpenPGP signature, if
+// SignatureURL is set, against the keyring at KeyringPath (or
+// keyring.DefaultPath()). It runs after DownloadArtifact and before
+// UpdatePackage so a tampered artifact never reaches Aurora. Packages
+// without a SignatureURL are treated as verified, since signing is
+// opt-in per manifest.
+func (p *Package) VerifySignature(mgr *transfer.Manager) *transfer.Job {
+  return mgr.Check(p.Project+"#verify", logWatcher{}, func(ctx context.Context) (interface{}, error) {
+    if p.SignatureURL == "" {
+      p.Verified = true
+      return nil, nil
+    }
+    p.Verified = false
 
-    if _, err := os.Stat(outpath); err == nil {
-      os.Remove(outpath)
+    keyringPath := p.KeyringPath
+    if keyringPath == "" {
+      keyringPath = keyring.DefaultPath()
     }
-    err := os.MkdirAll(outpath, 0777)
-    if err != nil && !os.IsExist(err) {
-      log.Print("Error while creating path ", outpath, " - ", err)
-      return
+    keys, err := keyring.ListKeys(keyringPath)
+    if err != nil {
+      return nil, err
     }
 
-    output, err := os.Create(outname)
+    sigReq, err := http.NewRequest("GET", p.SignatureURL, nil)
     if err != nil {
-      log.Print("Error while creating ", outname, " - ", err)
-      return
+      return nil, err
     }
-    defer output.Close()
+    sigResp, err := http.DefaultClient.Do(sigReq.WithContext(ctx))
+    if err != nil {
+      return nil, err
+    }
+    defer sigResp.Body.Close()
 
-    response, err := http.Get(p.Artifact)
+    artifact, err := os.Open(p.File)
     if err != nil {
-      log.Print("Error while downloading ", p.Artifact, " - ", err)
-      return
+      return nil, err
     }
-    defer response.Body.Close()
+    defer artifact.Close()
 
-    n, err := io.Copy(output, response.Body)
+    if _, err := openpgp.CheckDetachedSignature(keys, artifact, sigResp.Body); err != nil {
+      return nil, fmt.Errorf("signature verification failed for %s: %v", p.Project, err)
+    }
+    p.Verified = true
+    pkgLog(p, "verify").Info("signature verified")
+    return nil, nil
+  })
+}
+
+func (p *Package) CheckIfValidPackage(mgr *transfer.Manager) *transfer.Job {
+  return mgr.Check(p.Artifact+"#valid", logWatcher{}, func(ctx context.Context) (interface{}, error) {
+    req, err := http.NewRequest("HEAD", p.Artifact, nil)
     if err != nil {
-      log.Print("Error while downloading ", p.Artifact, " - ", err)
-      return
+      return nil, err
     }
-    log.Print(n, " bytes downloaded for ", p.Project)
-    p.File = outname
-    future <-p
-  }()
-  return future
+    response, err := http.DefaultClient.Do(req.WithContext(ctx))
+    if err != nil {
+      return nil, err
+    }
+    return stringInArray(response.Header.Get("content-type"), acceptableMIMETypes), nil
+  })
 }
 
-func (p *Package) CheckIfValidPackage() FuturePkg {
-  future := make(FuturePkg)
-  go func() {
+// applyValidity reads job's result, the validity check
+// CheckIfValidPackage submitted on p's behalf (possibly run by a
+// different package sharing p.Artifact), and sets p.Valid. Every
+// package sharing an Artifact must call this themselves, since only
+// one of them actually ran the check.
+func (p *Package) applyValidity(job *transfer.Job) {
+  valid, err := job.Wait()
+  if err != nil {
+    pkgLog(p, "validate").WithError(err).Warn("validity check failed")
     p.Valid = false
-    response, err := http.Get(p.Artifact)
-    if err == nil && stringInArray(response.Header.Get("content-type"), acceptableMIMETypes) {
-      p.Valid = true
-    }
-    future <- p
-  }()
-  return future
+    return
+  }
+  p.Valid, _ = valid.(bool)
 }
 
-func (p *Package) clusterOkToUpdate(cluster string) (bool, string) {
-  cmd := exec.Command("aurora", "package_versions", "--cluster=" + cluster, "jenkins", p.Project)
-  var out bytes.Buffer
-  cmd.Stdout = &out
-  err := cmd.Run()
+// clusterOkToUpdate asks cluster's scheduler for the package's known
+// versions over Thrift and compares the latest against p.Revision, in
+// place of the old exec.Command("aurora", "package_versions", ...) +
+// regex-over-stdout approach.
+func (p *Package) clusterOkToUpdate(clusters map[string]aurora.ClusterConfig, role, cluster string) (bool, string) {
+  client, err := aurora.NewClient(clusters, cluster)
   if err != nil {
     return true, err.Error()
   }
-  shas := shaMatch.FindAllString(out.String(), -1)
-  if len(shas) < 1 {
-    return true, "No shas found for " + p.Project + " in " + cluster
+  defer client.Close()
+
+  versions, err := client.PackageVersions(role, p.Project)
+  if err != nil {
+    return true, err.Error()
+  }
+  if len(versions) < 1 {
+    return true, "No versions found for " + p.Project + " in " + cluster
   }
-  latest := shas[len(shas)-1:len(shas)][0]
-  log.Print(p.Project, " latest sha in ", cluster, ": ", latest)
+  latest := versions[len(versions)-1].Revision
+  clusterLog(p, "check", cluster).WithField("latest", latest).Info("found latest revision")
   if p.Revision == latest {
     return false, p.Project + " does not need to be updated."
   } else {
@@ -161,158 +346,289 @@ func (p *Package) clusterOkToUpdate(cluster string) (bool, string) {
   }
 }
 
-func (p *Package) IsUpdateNeeded() FuturePkg {
-  future := make(FuturePkg)
+func (p *Package) IsUpdateNeeded(mgr *transfer.Manager, clusters map[string]aurora.ClusterConfig, role string) *transfer.Job {
   p.NeedUpdate = make(map[string]bool)
-  go func() {
-    for _, cluster := range AllClusters {
-      ok, status := p.clusterOkToUpdate(cluster)
-      log.Print("Packer status: " + status)
+  return mgr.Check(p.Project+"#update-needed", logWatcher{}, func(ctx context.Context) (interface{}, error) {
+    for _, cluster := range p.EffectiveClusters() {
+      ok, status := p.clusterOkToUpdate(clusters, role, cluster)
+      clusterLog(p, "check", cluster).Info(status)
       p.NeedUpdate[cluster] = ok
     }
-    future <- p
-  }()
-  return future
+    return nil, nil
+  })
 }
 
-func (p *Package) UpdatePackage(cluster string) FuturePkg {
-  future := make(FuturePkg)
-  p.Updated = make(map[string]bool)
-  go func() {
+func (p *Package) setClusterError(cluster string, err error) {
+  if p.ClusterErrors == nil {
+    p.ClusterErrors = make(map[string]string)
+  }
+  p.ClusterErrors[cluster] = err.Error()
+}
+
+func (p *Package) UpdatePackage(mgr *transfer.Manager, clusters map[string]aurora.ClusterConfig, cluster string) *transfer.Job {
+  if p.Updated == nil {
+    p.Updated = make(map[string]bool)
+  }
+  return mgr.Upload(p.Project+"#"+cluster, logWatcher{}, func(ctx context.Context) (interface{}, error) {
     current_user, _ := user.Current()
-    log.Print(p.Project, ": uploading " + p.File, " to " + current_user.Username + " packer in " + cluster)
-    cmd := exec.Command(
-        "aurora",
-        "package_add_version",
-        "--cluster=" + cluster,
-        "--metadata=" + p.Revision,
-        current_user.Username,
-        p.Project,
-        p.File,
-    )
-    var out bytes.Buffer
-    cmd.Stdout = &out
-    err := cmd.Run()
+    clusterLog(p, "upload", cluster).WithFields(logrus.Fields{
+      "file": p.File,
+      "user": current_user.Username,
+    }).Info("uploading package")
+
+    client, err := aurora.NewClient(clusters, cluster)
     if err != nil {
-      log.Print(p.Project + ": error: " + err.Error())
-      p.Updated[cluster] = false
-    } else {
-      log.Print(p.Project + ": " + out.String())
-      p.Updated[cluster] = true
+      return nil, err
     }
-    future <-p
-  }()
-  return future
-}
+    defer client.Close()
 
-func GetAllPackages(path string, futures *Futures) (pkgs []*Package) {
-  matches, _ := filepath.Glob(path)
+    if err := client.AddPackageVersion(current_user.Username, p.Project, p.File, p.Revision); err != nil {
+      return nil, err
+    }
+    clusterLog(p, "upload", cluster).Info("upload complete")
+    return nil, nil
+  })
+}
 
-  for _, v := range matches {
-    futures.AddFuture(decodeJsonFile(v))
+// applyUpdate reads job's result, the upload UpdatePackage submitted
+// for cluster on p's behalf, and records success or failure in
+// p.Updated/p.ClusterErrors. A package targeting several clusters
+// submits one UpdatePackage job per cluster, and those jobs run
+// concurrently, so p.Updated/p.ClusterErrors must only ever be written
+// from here, sequentially, after each job has finished, rather than
+// from inside the concurrently-running job closures themselves.
+func (p *Package) applyUpdate(cluster string, job *transfer.Job) {
+  if _, err := job.Wait(); err != nil {
+    p.Updated[cluster] = false
+    p.setClusterError(cluster, err)
+    return
   }
+  p.Updated[cluster] = true
+}
 
-  for ; futures.ItemsInChannels > 0; futures.ItemsInChannels-- {
-    pkgs = append(pkgs, <-futures.Channels)
-  }
-  return
+// pkgJob pairs a Package with the Job it submitted, so a phase whose
+// jobs can dedupe across packages (CheckIfValidPackage, DownloadArtifact)
+// can apply each job's result back to its own package once it
+// completes, rather than relying on whichever package's closure
+// actually ran.
+type pkgJob struct {
+  pkg *Package
+  job *transfer.Job
 }
 
-func (futures *Futures) BlockUntilComplete(reason string) {
-  log.Print(reason)
-  for ; futures.ItemsInChannels > 0; futures.ItemsInChannels-- {
-    <-futures.Channels  // we dont care whats in the channels... just that they completed
-  }
+// clusterJob pairs a Package and cluster with the Job UpdatePackage
+// submitted for them, so a phase that runs one job per cluster can
+// apply each job's result back sequentially once it completes, rather
+// than writing the shared Updated/ClusterErrors maps from inside the
+// concurrently-running job closures.
+type clusterJob struct {
+  pkg     *Package
+  cluster string
+  job     *transfer.Job
 }
 
-func (futures *Futures) AddFuture(future FuturePkg) {
-  go func() { for { futures.Channels <- <-future } }()
-  futures.ItemsInChannels++
+func GetAllPackages(globPath string) (pkgs []*Package) {
+  matches, _ := filepath.Glob(globPath)
+
+  for _, v := range matches {
+    p, err := decodeJsonFile(v)
+    if err != nil {
+      logger.WithField("file", v).Error(err)
+      continue
+    }
+    pkgs = append(pkgs, p)
+  }
+  return
 }
 
 func main() {
-  var project  string
-  var rootPath string
+  cfg, err := loadConfig()
+  if err != nil {
+    logger.Fatal("error loading jenkins_uploader.yaml: ", err)
+  }
+  if len(cfg.Clusters) > 0 {
+    AllClusters = cfg.Clusters
+  }
+  defaultRole := "jenkins"
+  if cfg.AuroraRole != "" {
+    defaultRole = cfg.AuroraRole
+  }
+
+  var project        string
+  var rootPath       string
+  var silent         bool
+  var noProgress     bool
+  var auroraRole     string
+  var auroraClusters string
+  var logFormat      string
+  var reportPath     string
+  var importKey      string
+  var keyringPath    string
 
   flag.StringVar(&project, "project", "*", "the project to work on")
   flag.StringVar(&rootPath, "rootpath", "~/workspace/revenue-deploy/config", "the root path to the dir with json files")
+  flag.BoolVar(&silent, "silent", false, "suppress all non-error log output")
+  flag.BoolVar(&noProgress, "no-progress", false, "disable download progress bars")
+  flag.StringVar(&auroraRole, "aurora-role", defaultRole, "the aurora role packages are checked under")
+  flag.StringVar(&auroraClusters, "aurora-clusters", "", "path to clusters.json (defaults to ~/.aurora/clusters.json)")
+  flag.StringVar(&logFormat, "log-format", "text", "log output format: text or json")
+  flag.StringVar(&reportPath, "report", "", "write a machine-readable JSON run report to this path")
+  flag.StringVar(&importKey, "import-key", "", "import an armored OpenPGP public key into the keyring and exit")
+  flag.StringVar(&keyringPath, "keyring-path", "", "keyring to import into (defaults to keyring.DefaultPath())")
   flag.Parse()
 
+  configureLogging(logFormat)
+  if silent {
+    logger.SetLevel(logrus.ErrorLevel)
+  }
+
+  if importKey != "" {
+    entity, err := keyring.NewKeyPair(keyringPath, importKey)
+    if err != nil {
+      logger.Fatal("error importing key: ", err)
+    }
+    logger.WithField("key_id", entity.PrimaryKey.KeyIdString()).Info("imported key")
+    os.Exit(0)
+  }
+
+  clusters, err := aurora.LoadClusters(auroraClusters)
+  if err != nil {
+    logger.Fatal("error loading aurora clusters: ", err)
+  }
 
   pwd, _ := os.Getwd()
-  downloadTmpDir := path.Join(pwd, "downloads_tmp")
+  downloadTmpDir := cfg.DownloadDir
+  if downloadTmpDir == "" {
+    downloadTmpDir = path.Join(pwd, "downloads_tmp")
+  }
+
+  bars := newProgressPool(silent || noProgress)
+  defer bars.stop()
 
-  futures := &Futures{Channels: make(FuturePkg), ItemsInChannels: 0}
+  ctx, cancel := context.WithCancel(context.Background())
+  sigs := make(chan os.Signal, 1)
+  signal.Notify(sigs, os.Interrupt)
+  go func() {
+    <-sigs
+    logger.Warn("interrupted, canceling in-flight transfers")
+    cancel()
+  }()
+
+  transferCfg := transfer.DefaultConfig()
+  if cfg.MaxConcurrentDownloads > 0 {
+    transferCfg.MaxConcurrentDownloads = cfg.MaxConcurrentDownloads
+  }
+  if cfg.MaxConcurrentUploads > 0 {
+    transferCfg.MaxConcurrentUploads = cfg.MaxConcurrentUploads
+  }
+  mgr := transfer.NewManager(ctx, transferCfg)
+  defer mgr.Stop()
 
-  pkgs := GetAllPackages(path.Join(rootPath, project + ".json"), futures)
+  pkgs := GetAllPackages(path.Join(rootPath, project + ".json"))
   if len(pkgs) < 1 {
-    log.Fatal("No packages found")
+    logger.Fatal("no packages found")
   }
 
   pkgsString := ""
   for _, p := range pkgs {
     pkgsString += p.Project + ", "
   }
-  log.Print("Starting projects: ", pkgsString)
+  logger.WithField("projects", pkgsString).Info("starting run")
 
 
+  var checks []pkgJob
   for _, p := range pkgs {
-    futures.AddFuture(p.CheckIfValidPackage())
+    checks = append(checks, pkgJob{p, p.CheckIfValidPackage(mgr)})
+  }
+  logger.Info("checking validity of packages")
+  for _, c := range checks {
+    c.pkg.applyValidity(c.job)
   }
-  futures.BlockUntilComplete("Checking validity of packages")
 
+  var jobs []*transfer.Job
   for _, p := range pkgs {
     if p.Valid {
-      log.Print(p.Project, " is valid")
-      futures.AddFuture(p.IsUpdateNeeded())
+      pkgLog(p, "validate").Info("package is valid")
+      jobs = append(jobs, p.IsUpdateNeeded(mgr, clusters, auroraRole))
     } else {
-      log.Print(p.Project, " is not valid")
+      pkgLog(p, "validate").Warn("package is not valid")
     }
   }
-  if futures.ItemsInChannels > 0 {
-    futures.BlockUntilComplete("Checking which packer clusters need to be updated")
+  if len(jobs) > 0 {
+    logger.Info("checking which packer clusters need to be updated")
+    mgr.Wait(jobs...)
   } else {
-    log.Fatal("Unable to process any projects!")
+    logger.Fatal("unable to process any projects")
   }
 
+  var downloads []pkgJob
   for _, p := range pkgs {
-    for _, cluster := range AllClusters {
+    for _, cluster := range p.EffectiveClusters() {
       if p.NeedUpdate[cluster] {
-        log.Print(p.Project + ": needs update")
-        futures.AddFuture(p.DownloadArtifact(downloadTmpDir))
+        pkgLog(p, "download").Info("needs update")
+        downloads = append(downloads, pkgJob{p, p.DownloadArtifact(mgr, downloadTmpDir, bars)})
         break  // break if any clusters needs update since we only need the file once for all clusters
       }
     }
   }
-  if futures.ItemsInChannels > 0 {
-    futures.BlockUntilComplete("Downloading packages")
+  if len(downloads) > 0 {
+    logger.Info("downloading packages")
+    for _, d := range downloads {
+      if err := d.pkg.applyDownload(d.job); err != nil {
+        pkgLog(d.pkg, "download").WithError(err).Error("download failed")
+      }
+    }
   } else {
-    log.Print("All packages are up to date. Exiting...")
+    logger.Info("all packages are up to date, exiting")
+    writeReport(reportPath, pkgs)
     os.Exit(0)
   }
 
+  jobs = nil
   for _, p := range pkgs {
-    for _, cluster := range AllClusters {
+    if p.File != "" {
+      jobs = append(jobs, p.VerifySignature(mgr))
+    }
+  }
+  if len(jobs) > 0 {
+    logger.Info("verifying artifact signatures")
+    mgr.Wait(jobs...)
+  }
+
+  var updates []clusterJob
+  for _, p := range pkgs {
+    if p.File != "" && !p.Verified {
+      pkgLog(p, "upload").Warn("skipping upload, signature did not verify")
+      continue
+    }
+    for _, cluster := range p.EffectiveClusters() {
       if p.NeedUpdate[cluster] {
-        futures.AddFuture(p.UpdatePackage(cluster))
+        updates = append(updates, clusterJob{p, cluster, p.UpdatePackage(mgr, clusters, cluster)})
       }
     }
   }
-  // we know there will be items in the channels because we would not have
-  // gotten this far if there wasnt because we would have stopped after realizing
-  // there was nothing to download.
-  futures.BlockUntilComplete("Updating packages")
+  // updates is only empty here if every package needing an update was
+  // skipped above for a failed signature check, because otherwise we
+  // would have stopped after realizing there was nothing to download.
+  logger.Info("updating packages")
+  for _, u := range updates {
+    u.pkg.applyUpdate(u.cluster, u.job)
+  }
 
   for _, p := range pkgs {
-    for _, cluster := range AllClusters {
+    for _, cluster := range p.EffectiveClusters() {
       if p.NeedUpdate[cluster] {
         if p.Updated[cluster] {
-          log.Println(p.Project + ": " + cluster + " was updated successfully")
+          clusterLog(p, "upload", cluster).Info("update successful")
         } else {
-          log.Println(p.Project + ": " + cluster + " was NOT updated successfully")
+          clusterLog(p, "upload", cluster).Error("update failed")
         }
       }
     }
   }
+
+  if err := writeReport(reportPath, pkgs); err != nil {
+    logger.WithError(err).Error("failed to write run report")
+  }
   os.Remove(downloadTmpDir)  // cleanup after yourself!
 }
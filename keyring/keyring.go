@@ -0,0 +1,97 @@
+// Package keyring manages the local OpenPGP keyring jenkins_uploader
+// verifies artifact signatures against before they're uploaded to
+// Aurora.
+package keyring
+
+import (
+  "errors"
+  "os"
+  "os/user"
+  "path/filepath"
+
+  "golang.org/x/crypto/openpgp"
+)
+
+// DefaultPath is where the keyring lives unless a package's
+// KeyringPath field overrides it.
+func DefaultPath() string {
+  u, err := user.Current()
+  if err != nil {
+    return ""
+  }
+  return filepath.Join(u.HomeDir, ".jenkins_uploader", "keyring.gpg")
+}
+
+// checkDir stats dir and, only when create is true, makes it if
+// missing. List/verify callers pass create=false so they fail loudly
+// against a missing keyring directory instead of silently running
+// against the empty one they just created; NewKeyPair is the only
+// caller that passes create=true.
+func checkDir(dir string, create bool) error {
+  if _, err := os.Stat(dir); err != nil {
+    if !os.IsNotExist(err) {
+      return err
+    }
+    if !create {
+      return errors.New("keyring directory " + dir + " does not exist, run import first")
+    }
+    return os.MkdirAll(dir, 0700)
+  }
+  return nil
+}
+
+// ListKeys reads every entity in the keyring at path (DefaultPath()
+// if empty). It does not create the keyring directory.
+func ListKeys(path string) (openpgp.EntityList, error) {
+  if path == "" {
+    path = DefaultPath()
+  }
+  if err := checkDir(filepath.Dir(path), false); err != nil {
+    return nil, err
+  }
+  f, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+  return openpgp.ReadKeyRing(f)
+}
+
+// NewKeyPair reads an armored OpenPGP public key from keyFile and
+// appends it to the keyring at path (DefaultPath() if empty). It is
+// the only keyring operation allowed to create the keyring directory,
+// since importing is an explicit, deliberate action.
+func NewKeyPair(path, keyFile string) (*openpgp.Entity, error) {
+  if path == "" {
+    path = DefaultPath()
+  }
+  if err := checkDir(filepath.Dir(path), true); err != nil {
+    return nil, err
+  }
+
+  in, err := os.Open(keyFile)
+  if err != nil {
+    return nil, err
+  }
+  defer in.Close()
+
+  entities, err := openpgp.ReadArmoredKeyRing(in)
+  if err != nil {
+    return nil, err
+  }
+  if len(entities) == 0 {
+    return nil, errors.New("no keys found in " + keyFile)
+  }
+
+  out, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+  if err != nil {
+    return nil, err
+  }
+  defer out.Close()
+
+  entity := entities[0]
+  if err := entity.Serialize(out); err != nil {
+    return nil, err
+  }
+  return entity, nil
+}
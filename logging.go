@@ -0,0 +1,37 @@
+package main
+
+import (
+  "github.com/sirupsen/logrus"
+)
+
+// logger is the run's structured logger. Every phase attaches
+// project/revision/cluster/phase fields so a Jenkins job invoking this
+// tool can parse results instead of tailing free-form log lines.
+var logger = logrus.New()
+
+// configureLogging sets logger's output format, "text" (the default,
+// human-readable) or "json".
+func configureLogging(format string) {
+  switch format {
+  case "json":
+    logger.Formatter = &logrus.JSONFormatter{}
+  default:
+    logger.Formatter = &logrus.TextFormatter{}
+  }
+}
+
+// pkgLog returns an entry pre-populated with p's project/revision and
+// the current phase.
+func pkgLog(p *Package, phase string) *logrus.Entry {
+  return logger.WithFields(logrus.Fields{
+    "project":  p.Project,
+    "revision": p.Revision,
+    "phase":    phase,
+  })
+}
+
+// clusterLog is pkgLog with a cluster field added, for the phases that
+// operate against a single Aurora cluster.
+func clusterLog(p *Package, phase, cluster string) *logrus.Entry {
+  return pkgLog(p, phase).WithField("cluster", cluster)
+}
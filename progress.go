@@ -0,0 +1,46 @@
+package main
+
+import (
+  "github.com/cheggaaa/pb/v3"
+)
+
+// progressPool stacks per-project download bars into a single
+// multi-bar display so concurrent downloads render cleanly instead of
+// clobbering each other's output. A disabled pool (--silent or
+// --no-progress) hands out bars that are never started, so callers can
+// write through them unconditionally.
+type progressPool struct {
+  pool     *pb.Pool
+  disabled bool
+}
+
+// newProgressPool creates a pool. When disabled is true, bars vended
+// by newBar count bytes but never render.
+func newProgressPool(disabled bool) *progressPool {
+  pp := &progressPool{disabled: disabled}
+  if !disabled {
+    pp.pool = pb.NewPool()
+    pp.pool.Start()
+  }
+  return pp
+}
+
+// newBar returns a progress bar for project showing bytes/sec and ETA
+// against total bytes, added to the shared pool if one is running.
+func (pp *progressPool) newBar(project string, total int64) *pb.ProgressBar {
+  bar := pb.New64(total).Set("prefix", project+": ")
+  if pp.disabled {
+    return bar
+  }
+  pp.pool.Add(bar)
+  bar.Start()
+  return bar
+}
+
+// stop shuts down the shared pool, if any. It must be called once all
+// bars vended by newBar have finished.
+func (pp *progressPool) stop() {
+  if pp.pool != nil {
+    pp.pool.Stop()
+  }
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+  "encoding/json"
+  "os"
+)
+
+// ClusterReport is one cluster's outcome within a PackageReport.
+type ClusterReport struct {
+  Needed  bool   `json:"needed"`
+  Updated bool   `json:"updated"`
+  Error   string `json:"error,omitempty"`
+}
+
+// PackageReport is the machine-readable record of what happened to a
+// single package during a run, so a Jenkins post-build step can parse
+// results instead of tailing logs.
+type PackageReport struct {
+  Project    string                   `json:"project"`
+  Revision   string                   `json:"revision"`
+  Valid      bool                     `json:"valid"`
+  Verified   bool                     `json:"verified"`
+  PerCluster map[string]ClusterReport `json:"per_cluster"`
+}
+
+// buildReport summarizes pkgs' outcomes across each package's
+// effective cluster set.
+func buildReport(pkgs []*Package) []PackageReport {
+  report := make([]PackageReport, 0, len(pkgs))
+  for _, p := range pkgs {
+    pr := PackageReport{
+      Project:    p.Project,
+      Revision:   p.Revision,
+      Valid:      p.Valid,
+      Verified:   p.Verified,
+      PerCluster: make(map[string]ClusterReport),
+    }
+    for _, cluster := range p.EffectiveClusters() {
+      pr.PerCluster[cluster] = ClusterReport{
+        Needed:  p.NeedUpdate[cluster],
+        Updated: p.Updated[cluster],
+        Error:   p.ClusterErrors[cluster],
+      }
+    }
+    report = append(report, pr)
+  }
+  return report
+}
+
+// writeReport writes pkgs' run report to path as JSON. A blank path
+// is a no-op, since --report is optional.
+func writeReport(path string, pkgs []*Package) error {
+  if path == "" {
+    return nil
+  }
+
+  f, err := os.Create(path)
+  if err != nil {
+    return err
+  }
+  defer f.Close()
+
+  enc := json.NewEncoder(f)
+  enc.SetIndent("", "  ")
+  return enc.Encode(buildReport(pkgs))
+}
@@ -0,0 +1,213 @@
+// Package transfer provides a bounded worker-pool for running package
+// phases (downloads, uploads, and the validity/update checks that gate
+// them) instead of firing an unbounded goroutine per package. It is
+// modeled on Docker's transfer/upload/download managers: jobs are keyed
+// so identical work dedupes to a single run, failures are retried with
+// exponential backoff, and every job carries a context.Context so a
+// SIGINT can cancel in-flight work cleanly.
+package transfer
+
+import (
+  "context"
+  "fmt"
+  "sync"
+  "time"
+)
+
+// Watcher is notified of progress as jobs run. Package operations
+// subscribe a Watcher when they submit a job so callers can surface
+// progress (e.g. a download progress bar) without the manager knowing
+// anything about presentation.
+type Watcher interface {
+  OnProgress(key, kind, msg string)
+}
+
+// JobFunc is the work performed for a single job. It is retried with
+// exponential backoff when it returns an error, up to MaxRetries times,
+// unless ctx is canceled first. Its result is handed back to every
+// caller that submitted against the same Key, not just whichever one
+// happened to run it, so JobFunc must return everything a caller needs
+// rather than relying on side effects against state only it can see.
+type JobFunc func(ctx context.Context) (interface{}, error)
+
+// Job is a single unit of work submitted to a Manager. Jobs sharing a
+// Key dedupe while the first is still in flight: only that submission
+// runs JobFunc, and every submitter's Wait returns the same result. A
+// submission made after the job has finished runs JobFunc again.
+type Job struct {
+  Key  string
+  Kind string
+
+  fn     JobFunc
+  done   chan struct{}
+  result interface{}
+  err    error
+
+  watchersMu sync.Mutex
+  watchers   []Watcher
+}
+
+// Wait blocks until the job (or the job it was deduped against)
+// finishes and returns its result, or its error if it failed.
+func (j *Job) Wait() (interface{}, error) {
+  <-j.done
+  return j.result, j.err
+}
+
+// addWatcher subscribes w to progress notifications. It may run
+// concurrently with notify, since a job already in flight can pick up
+// new watchers from later callers sharing its Key.
+func (j *Job) addWatcher(w Watcher) {
+  if w == nil {
+    return
+  }
+  j.watchersMu.Lock()
+  j.watchers = append(j.watchers, w)
+  j.watchersMu.Unlock()
+}
+
+func (j *Job) notify(msg string) {
+  j.watchersMu.Lock()
+  watchers := append([]Watcher(nil), j.watchers...)
+  j.watchersMu.Unlock()
+  for _, w := range watchers {
+    w.OnProgress(j.Key, j.Kind, msg)
+  }
+}
+
+// Config controls a Manager's concurrency and retry behavior.
+type Config struct {
+  MaxConcurrentDownloads int
+  MaxConcurrentUploads   int
+  MaxRetries             int
+  InitialBackoff         time.Duration
+}
+
+// DefaultConfig mirrors the limits Docker's registry client defaults
+// to: a handful of concurrent transfers per direction and a short
+// backoff so a flaky Jenkins box doesn't stall the whole run.
+func DefaultConfig() Config {
+  return Config{
+    MaxConcurrentDownloads: 3,
+    MaxConcurrentUploads:   3,
+    MaxRetries:             3,
+    InitialBackoff:         500 * time.Millisecond,
+  }
+}
+
+// Manager runs download and upload jobs on two independently sized
+// worker pools, deduplicating jobs by Key and retrying failures with
+// exponential backoff. Canceling the context it was created with
+// cancels every in-flight and future job, so main can tie it to
+// SIGINT.
+type Manager struct {
+  cfg Config
+  ctx context.Context
+
+  mu       sync.Mutex
+  inFlight map[string]*Job
+
+  downloads chan *Job
+  uploads   chan *Job
+
+  wg sync.WaitGroup
+}
+
+// NewManager creates a Manager with cfg's concurrency limits and
+// starts its worker pools. Job funcs receive ctx (or a context derived
+// from it), so canceling ctx cancels in-flight work. Callers should
+// call Stop when done to shut the pools down.
+func NewManager(ctx context.Context, cfg Config) *Manager {
+  m := &Manager{
+    cfg:       cfg,
+    ctx:       ctx,
+    inFlight:  make(map[string]*Job),
+    downloads: make(chan *Job),
+    uploads:   make(chan *Job),
+  }
+  for i := 0; i < cfg.MaxConcurrentDownloads; i++ {
+    m.wg.Add(1)
+    go m.work(m.downloads)
+  }
+  for i := 0; i < cfg.MaxConcurrentUploads; i++ {
+    m.wg.Add(1)
+    go m.work(m.uploads)
+  }
+  return m
+}
+
+func (m *Manager) work(jobs chan *Job) {
+  defer m.wg.Done()
+  for job := range jobs {
+    job.err = nil
+    for attempt := 0; ; attempt++ {
+      job.result, job.err = job.fn(m.ctx)
+      if job.err == nil || m.ctx.Err() != nil || attempt >= m.cfg.MaxRetries {
+        break
+      }
+      backoff := m.cfg.InitialBackoff * time.Duration(1<<uint(attempt))
+      job.notify(fmt.Sprintf("attempt %d failed: %v, retrying in %s", attempt+1, job.err, backoff))
+      time.Sleep(backoff)
+    }
+    m.mu.Lock()
+    delete(m.inFlight, job.Key)
+    m.mu.Unlock()
+    close(job.done)
+  }
+}
+
+// submit enqueues fn under key/kind on the given pool, deduping
+// against any job already in flight for the same key.
+func (m *Manager) submit(pool chan *Job, key, kind string, watcher Watcher, fn JobFunc) *Job {
+  m.mu.Lock()
+  if job, ok := m.inFlight[key]; ok {
+    m.mu.Unlock()
+    job.addWatcher(watcher)
+    return job
+  }
+  job := &Job{Key: key, Kind: kind, fn: fn, done: make(chan struct{})}
+  job.addWatcher(watcher)
+  m.inFlight[key] = job
+  m.mu.Unlock()
+
+  pool <- job
+  return job
+}
+
+// Download submits a download job keyed by artifact URL so two
+// packages pointing at the same artifact share a single transfer.
+func (m *Manager) Download(artifactURL string, watcher Watcher, fn JobFunc) *Job {
+  return m.submit(m.downloads, artifactURL, "download", watcher, fn)
+}
+
+// Upload submits an upload job keyed by project+cluster.
+func (m *Manager) Upload(key string, watcher Watcher, fn JobFunc) *Job {
+  return m.submit(m.uploads, key, "upload", watcher, fn)
+}
+
+// Check submits a non-transfer job (validity check, update-needed
+// check) to the download pool, since it competes for the same
+// "don't hammer Jenkins/Aurora" budget as a download.
+func (m *Manager) Check(key string, watcher Watcher, fn JobFunc) *Job {
+  return m.submit(m.downloads, key, "check", watcher, fn)
+}
+
+// Wait blocks until every job in jobs has completed and returns the
+// first error encountered among them, if any.
+func (m *Manager) Wait(jobs ...*Job) error {
+  var firstErr error
+  for _, job := range jobs {
+    if _, err := job.Wait(); err != nil && firstErr == nil {
+      firstErr = err
+    }
+  }
+  return firstErr
+}
+
+// Stop closes the worker pools and waits for in-flight jobs to drain.
+// It must only be called once no further jobs will be submitted.
+func (m *Manager) Stop() {
+  close(m.downloads)
+  close(m.uploads)
+  m.wg.Wait()
+}